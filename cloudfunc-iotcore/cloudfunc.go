@@ -7,12 +7,17 @@ package main
 
 import (
 	"context"
-	b64 "encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
-	cloudiot "google.golang.org/api/cloudiot/v1"
+	"github.com/xinyinglim/articles/internal/configarchive"
+	"github.com/xinyinglim/articles/internal/devicelocator"
+	"github.com/xinyinglim/articles/internal/iotclient"
+	"github.com/xinyinglim/articles/internal/rules"
+	"github.com/xinyinglim/articles/internal/weather"
 )
 
 type FanConfig struct {
@@ -20,46 +25,131 @@ type FanConfig struct {
 	Speed int  `json:"speed"`
 }
 
-func UpdateWeather(w http.ResponseWriter, r *http.Request) {
+const (
+	projectID  = "YOUR-GCP-PROJECT-ID"
+	location   = "REGISTRY-LOCATION"
+	registryID = "REGISTRY-ID"
+
+	// archiveBucket is where every config push is recorded, before and
+	// after the call, for history and rollback.
+	archiveBucket = "YOUR-GCS-BUCKET"
+
+	// rulesConfigPath is the YAML file mapping weather observations to
+	// fan actions, deployed alongside the function.
+	rulesConfigPath = "rules.yaml"
+
+	// observationCacheTTL and observationGridSizeDeg bound how often the
+	// weather source is actually called: every device within the same
+	// grid cell shares an observation for the TTL.
+	observationCacheTTL    = 10 * time.Minute
+	observationGridSizeDeg = 0.25
+)
+
+// observationSource is shared by every UpdateWeather invocation in this
+// instance, so cached observations outlive a single request.
+var observationSource = weather.NewCaching(weather.NewOpenWeatherMap(os.Getenv("OPENWEATHERMAP_API_KEY")), observationCacheTTL, observationGridSizeDeg)
 
+// deviceDecision is one device's entry in UpdateWeather's JSON response,
+// so operators can see why each fan was set the way it was.
+type deviceDecision struct {
+	DeviceID    string              `json:"deviceId"`
+	Lat         float64             `json:"lat,omitempty"`
+	Lon         float64             `json:"lon,omitempty"`
+	Observation weather.Observation `json:"observation,omitempty"`
+	FanConfig   FanConfig           `json:"fanConfig,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// newClient builds an iotclient.Client with its config archive wired in,
+// so every push made through it is recorded to archiveBucket.
+func newClient(ctx context.Context) (*iotclient.Client, error) {
+	client, err := iotclient.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := configarchive.New(ctx, archiveBucket)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	client.SetArchiver(archive)
+
+	return client, nil
+}
+
+// UpdateWeather resolves each device's location, fetches the current
+// weather there, and maps it to a FanConfig through rules.yaml, pushing
+// the result to that device alone. The response body is a JSON array of
+// per-device decisions so operators can see why each fan was set the
+// way it was.
+func UpdateWeather(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
-	service, err := cloudiot.NewService(ctx)
+
+	client, err := newClient(ctx)
 	if err != nil {
 		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
 		return
 	}
-	deviceService := cloudiot.NewProjectsLocationsRegistriesDevicesService(service)
-	sunnyConfigData := FanConfig{
-		On:    true,
-		Speed: 20,
+	defer client.Close()
+
+	engine, err := rules.LoadFile(rulesConfigPath)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
 	}
-	bytes, err := json.Marshal(sunnyConfigData)
+
+	registryPath := fmt.Sprintf("projects/%s/locations/%s/registries/%s", projectID, location, registryID)
+	devices, err := client.ListDevices(ctx, registryPath, nil)
 	if err != nil {
 		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
 		return
 	}
 
-	encodedString := b64.StdEncoding.EncodeToString(bytes)
-	configRequest := cloudiot.ModifyCloudToDeviceConfigRequest{
-		BinaryData: encodedString,
+	decisions := make([]deviceDecision, 0, len(devices))
+	for _, d := range devices {
+		decisions = append(decisions, decideDevice(ctx, client, observationSource, engine, d.GetName()))
 	}
 
-	projectID := "YOUR-GCP-PROJECT-ID"
-	location := "REGISTRY-LOCATION"
-	registryID := "REGISTRY-ID"
-	deviceID := "DEVICE-ID"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decisions)
+}
 
-	devicePath := fmt.Sprintf("projects/%s/locations/%s/registries/%s/devices/%s", projectID, location, registryID, deviceID)
-	call := deviceService.ModifyCloudToDeviceConfig(devicePath, &configRequest)
-	call.Context(ctx)
+func decideDevice(ctx context.Context, client *iotclient.Client, source weather.WeatherSource, engine *rules.Engine, devicePath string) deviceDecision {
+	// Fetch the device once: ListDevices doesn't populate Config, so the
+	// optimistic-concurrency version for PushConfigVersion needs this
+	// live GetDevice call, and lat/lon are read from the same fresh copy.
+	device, err := client.GetDevice(ctx, devicePath)
+	if err != nil {
+		return deviceDecision{DeviceID: devicePath, Error: err.Error()}
+	}
 
-	_, err = call.Do()
+	lat, lon, err := devicelocator.FromDevice(device)
 	if err != nil {
-		fmt.Printf("%v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("500 - Something bad happened!"))
-		return
+		return deviceDecision{DeviceID: devicePath, Error: err.Error()}
+	}
+
+	obs, err := source.Current(ctx, lat, lon)
+	if err != nil {
+		return deviceDecision{DeviceID: devicePath, Lat: lat, Lon: lon, Error: err.Error()}
 	}
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Success!"))
+
+	action := engine.Decide(obs)
+	cfg := FanConfig{On: action.On, Speed: action.Speed}
+	bytes, err := json.Marshal(cfg)
+	if err != nil {
+		return deviceDecision{DeviceID: devicePath, Lat: lat, Lon: lon, Observation: obs, Error: err.Error()}
+	}
+
+	if _, err := client.PushConfigVersion(ctx, devicePath, bytes, device.GetConfig().GetVersion()); err != nil {
+		return deviceDecision{DeviceID: devicePath, Lat: lat, Lon: lon, Observation: obs, Error: err.Error()}
+	}
+
+	return deviceDecision{DeviceID: devicePath, Lat: lat, Lon: lon, Observation: obs, FanConfig: cfg}
 }
@@ -0,0 +1,104 @@
+// gcloud functions deploy History --runtime go111 --trigger-http --allow-unauthenticated
+// gcloud functions deploy Rollback --runtime go111 --trigger-http --allow-unauthenticated
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/xinyinglim/articles/internal/configarchive"
+)
+
+// historyLimit bounds how many archived configs a single /history
+// request returns.
+const historyLimit = 20
+
+// History streams the last N archived configs for a device, newest
+// first, by listing the archive bucket prefix.
+func History(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	devicePath := r.URL.Query().Get("device")
+	if devicePath == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("400 - missing device parameter"))
+		return
+	}
+
+	archive, err := configarchive.New(ctx, archiveBucket)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+
+	configs, err := archive.History(ctx, devicePath, historyLimit)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(configs)
+}
+
+// Rollback re-pushes the archived config for a device at a given
+// version, so an operator can undo a bad push.
+func Rollback(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	devicePath := r.URL.Query().Get("device")
+	version, parseErr := strconv.ParseInt(r.URL.Query().Get("version"), 10, 64)
+	if devicePath == "" || parseErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("400 - missing or invalid device/version parameter"))
+		return
+	}
+
+	archive, err := configarchive.New(ctx, archiveBucket)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+
+	payload, err := archive.Fetch(ctx, devicePath, version)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+
+	client, err := newClient(ctx)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+	defer client.Close()
+
+	device, err := client.GetDevice(ctx, devicePath)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+
+	if _, err := client.PushConfigVersion(ctx, devicePath, payload, device.GetConfig().GetVersion()); err != nil {
+		fmt.Printf("%v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("500 - Something bad happened!"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Success!"))
+}
@@ -0,0 +1,45 @@
+package main
+
+// DeviceState is the last-reported telemetry for a device, decoded from
+// its Pub/Sub payload.
+type DeviceState struct {
+	DeviceID    string
+	TempC       float64
+	HumidityPct float64
+}
+
+// Policy turns a device's reported state into the FanConfig it should be
+// running.
+type Policy interface {
+	Decide(state DeviceState) FanConfig
+}
+
+// thresholdPolicy is the default Policy: it ramps the fan speed linearly
+// above a comfort temperature and leaves the fan off below it.
+type thresholdPolicy struct {
+	comfortTempC float64
+}
+
+// DefaultPolicy returns the threshold Policy used when none is supplied:
+// speed = clamp((tempC-20)*10, 0, 100), off below 20C.
+func DefaultPolicy() Policy {
+	return thresholdPolicy{comfortTempC: 20}
+}
+
+func (p thresholdPolicy) Decide(state DeviceState) FanConfig {
+	speed := clamp(int((state.TempC-p.comfortTempC)*10), 0, 100)
+	return FanConfig{
+		On:    speed > 0,
+		Speed: speed,
+	}
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
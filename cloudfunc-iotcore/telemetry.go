@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"cloud.google.com/go/pubsub"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+
+	"github.com/xinyinglim/articles/internal/iotclient"
+)
+
+// telemetrySubscriptionID is the Pub/Sub subscription draining the
+// registry's telemetry topic.
+const telemetrySubscriptionID = "REGISTRY-TELEMETRY-SUBSCRIPTION"
+
+// keyfileEnvVar names the environment variable holding the path to a
+// service account JSON keyfile, used so this binary can authenticate
+// to Pub/Sub outside of GCF, where application default credentials
+// aren't available.
+const keyfileEnvVar = "IOT_WEATHER_KEYFILE"
+
+// telemetryPayload is the device-reported JSON body published on the
+// registry's telemetry topic.
+type telemetryPayload struct {
+	TempC       float64 `json:"tempC"`
+	HumidityPct float64 `json:"humidityPct"`
+}
+
+// SubscribeTelemetry pulls device telemetry from the registry's Pub/Sub
+// subscription, recomputes the desired FanConfig for the reporting
+// device via Policy, and pushes it back through the gRPC
+// ModifyCloudToDeviceConfig call, passing the device's last-seen
+// config version so a racing update is rejected rather than clobbered.
+func SubscribeTelemetry(ctx context.Context) error {
+	var opts []option.ClientOption
+	if os.Getenv(keyfileEnvVar) != "" {
+		ts, err := tokenSource(ctx)
+		if err != nil {
+			return fmt.Errorf("telemetry: loading credentials: %w", err)
+		}
+		opts = append(opts, option.WithTokenSource(ts))
+	}
+
+	psClient, err := pubsub.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return fmt.Errorf("telemetry: creating pubsub client: %w", err)
+	}
+	defer psClient.Close()
+
+	iotClient, err := newClient(ctx)
+	if err != nil {
+		return fmt.Errorf("telemetry: creating iot client: %w", err)
+	}
+	defer iotClient.Close()
+
+	policy := DefaultPolicy()
+	registryPath := fmt.Sprintf("projects/%s/locations/%s/registries/%s", projectID, location, registryID)
+
+	sub := psClient.Subscription(telemetrySubscriptionID)
+	return sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		if err := handleTelemetry(ctx, iotClient, policy, registryPath, m); err != nil {
+			fmt.Printf("%v\n", err)
+			m.Nack()
+			return
+		}
+		m.Ack()
+	})
+}
+
+func handleTelemetry(ctx context.Context, iotClient *iotclient.Client, policy Policy, registryPath string, m *pubsub.Message) error {
+	deviceID := m.Attributes["deviceId"]
+	if deviceID == "" {
+		return fmt.Errorf("telemetry: message missing deviceId attribute")
+	}
+
+	var payload telemetryPayload
+	if err := json.Unmarshal(m.Data, &payload); err != nil {
+		return fmt.Errorf("telemetry: decoding payload for %s: %w", deviceID, err)
+	}
+
+	devicePath := fmt.Sprintf("%s/devices/%s", registryPath, deviceID)
+	device, err := iotClient.GetDevice(ctx, devicePath)
+	if err != nil {
+		return err
+	}
+
+	cfg := policy.Decide(DeviceState{
+		DeviceID:    deviceID,
+		TempC:       payload.TempC,
+		HumidityPct: payload.HumidityPct,
+	})
+	bytes, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("telemetry: marshaling config for %s: %w", deviceID, err)
+	}
+
+	if _, err := iotClient.PushConfigVersion(ctx, devicePath, bytes, device.GetConfig().GetVersion()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// tokenSource loads a service account keyfile named by keyfileEnvVar and
+// returns an oauth2.TokenSource scoped for Pub/Sub, so SubscribeTelemetry
+// can run standalone instead of only inside Google Cloud Functions.
+func tokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	keyPath := os.Getenv(keyfileEnvVar)
+	if keyPath == "" {
+		return nil, fmt.Errorf("%s not set", keyfileEnvVar)
+	}
+	keyBytes, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", keyPath, err)
+	}
+	jwtConfig, err := google.JWTConfigFromJSON(keyBytes, pubsub.ScopeCloudPlatform)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", keyPath, err)
+	}
+	return jwtConfig.TokenSource(ctx), nil
+}
@@ -0,0 +1,168 @@
+// Package configarchive persists every device config push to GCS, so a
+// fleet controller built on iotclient has an auditable, replayable
+// history instead of fire-and-forget RPCs.
+package configarchive
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// record is the JSON object written to GCS, both before a push (where
+// Version is unset) and after a successful one.
+type record struct {
+	DevicePath string    `json:"devicePath"`
+	Timestamp  time.Time `json:"timestamp"`
+	Payload    string    `json:"payload"` // base64-encoded BinaryData
+	Version    int64     `json:"version,omitempty"`
+}
+
+// ArchivedConfig is a single push recorded in the archive, decoded back
+// into its raw payload for callers.
+type ArchivedConfig struct {
+	DevicePath string
+	Timestamp  time.Time
+	Payload    []byte
+	Version    int64
+}
+
+// Archive writes config pushes to a GCS bucket and reads them back for
+// history and rollback. It implements iotclient.Archiver.
+type Archive struct {
+	bucket *storage.BucketHandle
+}
+
+// New opens an Archive backed by the given GCS bucket.
+func New(ctx context.Context, bucketName string, opts ...option.ClientOption) (*Archive, error) {
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("configarchive: creating storage client: %w", err)
+	}
+	return &Archive{bucket: client.Bucket(bucketName)}, nil
+}
+
+// RecordRequest writes the about-to-be-issued config as a pending object
+// and returns its object name as the record ID for RecordResult.
+func (a *Archive) RecordRequest(ctx context.Context, devicePath string, payload []byte) (string, error) {
+	rec := record{
+		DevicePath: devicePath,
+		Timestamp:  time.Now().UTC(),
+		Payload:    b64.StdEncoding.EncodeToString(payload),
+	}
+	name := fmt.Sprintf("%s/pending/%d.json", slug(devicePath), rec.Timestamp.UnixNano())
+	if err := a.writeObject(ctx, name, rec); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// RecordResult writes a companion object keyed by the resulting config
+// version, so Fetch can look a rollback target up directly.
+func (a *Archive) RecordResult(ctx context.Context, recordID string, devicePath string, payload []byte, version int64) error {
+	rec := record{
+		DevicePath: devicePath,
+		Timestamp:  time.Now().UTC(),
+		Payload:    b64.StdEncoding.EncodeToString(payload),
+		Version:    version,
+	}
+	name := fmt.Sprintf("%s/configs/v%d.json", slug(devicePath), version)
+	return a.writeObject(ctx, name, rec)
+}
+
+// History returns up to limit of the most recently pushed configs for
+// devicePath, newest first.
+func (a *Archive) History(ctx context.Context, devicePath string, limit int) ([]ArchivedConfig, error) {
+	it := a.bucket.Objects(ctx, &storage.Query{Prefix: slug(devicePath) + "/configs/"})
+
+	var configs []ArchivedConfig
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("configarchive: listing history for %s: %w", devicePath, err)
+		}
+		rec, err := a.readObject(ctx, attrs.Name)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, toArchivedConfig(rec))
+	}
+
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Version > configs[j].Version })
+	if len(configs) > limit {
+		configs = configs[:limit]
+	}
+	return configs, nil
+}
+
+// Fetch returns the archived payload pushed to devicePath as the given
+// config version, for use as a rollback target.
+func (a *Archive) Fetch(ctx context.Context, devicePath string, version int64) ([]byte, error) {
+	name := fmt.Sprintf("%s/configs/v%d.json", slug(devicePath), version)
+	rec, err := a.readObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := b64.StdEncoding.DecodeString(rec.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("configarchive: decoding payload for %s v%d: %w", devicePath, version, err)
+	}
+	return payload, nil
+}
+
+func (a *Archive) writeObject(ctx context.Context, name string, rec record) error {
+	w := a.bucket.Object(name).NewWriter(ctx)
+	if err := json.NewEncoder(w).Encode(rec); err != nil {
+		w.Close()
+		return fmt.Errorf("configarchive: encoding %s: %w", name, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("configarchive: writing %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *Archive) readObject(ctx context.Context, name string) (record, error) {
+	r, err := a.bucket.Object(name).NewReader(ctx)
+	if err != nil {
+		return record{}, fmt.Errorf("configarchive: opening %s: %w", name, err)
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return record{}, fmt.Errorf("configarchive: reading %s: %w", name, err)
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return record{}, fmt.Errorf("configarchive: decoding %s: %w", name, err)
+	}
+	return rec, nil
+}
+
+func toArchivedConfig(rec record) ArchivedConfig {
+	payload, _ := b64.StdEncoding.DecodeString(rec.Payload)
+	return ArchivedConfig{
+		DevicePath: rec.DevicePath,
+		Timestamp:  rec.Timestamp,
+		Payload:    payload,
+		Version:    rec.Version,
+	}
+}
+
+// slug turns a device resource path into a GCS-object-name-safe prefix.
+func slug(devicePath string) string {
+	return strings.ReplaceAll(devicePath, "/", "_")
+}
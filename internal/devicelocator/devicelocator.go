@@ -0,0 +1,62 @@
+// Package devicelocator resolves a device's latitude/longitude from its
+// Cloud IoT Core metadata.
+package devicelocator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	iotpb "google.golang.org/genproto/googleapis/cloud/iot/v1"
+
+	"github.com/xinyinglim/articles/internal/iotclient"
+)
+
+// Locator reads a device's lat/lon out of its metadata via the gRPC
+// GetDevice call.
+type Locator struct {
+	client *iotclient.Client
+}
+
+// New returns a Locator that looks devices up through client.
+func New(client *iotclient.Client) *Locator {
+	return &Locator{client: client}
+}
+
+// Locate fetches devicePath and reads its Metadata["lat"] and
+// Metadata["lon"] values. Callers that already hold a freshly-fetched
+// *iotpb.Device (e.g. because they also need its current config
+// version) should call FromDevice directly instead, to avoid a second
+// GetDevice RPC.
+func (l *Locator) Locate(ctx context.Context, devicePath string) (lat, lon float64, err error) {
+	device, err := l.client.GetDevice(ctx, devicePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	return FromDevice(device)
+}
+
+// FromDevice reads lat/lon out of an already-fetched device's metadata.
+func FromDevice(device *iotpb.Device) (lat, lon float64, err error) {
+	devicePath := device.GetName()
+	metadata := device.GetMetadata()
+
+	latStr, ok := metadata["lat"]
+	if !ok {
+		return 0, 0, fmt.Errorf("devicelocator: device %s has no lat metadata", devicePath)
+	}
+	lonStr, ok := metadata["lon"]
+	if !ok {
+		return 0, 0, fmt.Errorf("devicelocator: device %s has no lon metadata", devicePath)
+	}
+
+	lat, err = strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("devicelocator: device %s has invalid lat metadata: %w", devicePath, err)
+	}
+	lon, err = strconv.ParseFloat(lonStr, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("devicelocator: device %s has invalid lon metadata: %w", devicePath, err)
+	}
+	return lat, lon, nil
+}
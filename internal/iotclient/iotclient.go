@@ -0,0 +1,270 @@
+// Package iotclient wraps the gRPC Cloud IoT Core DeviceManagerClient so
+// callers can push a config to every device in a registry that matches a
+// filter, instead of hard-coding a single device path.
+package iotclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	iot "cloud.google.com/go/iot/apiv1"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	iotpb "google.golang.org/genproto/googleapis/cloud/iot/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// maxWorkers bounds how many ModifyCloudToDeviceConfig calls are
+// in flight at once, so a large registry doesn't open thousands of
+// concurrent RPCs.
+const maxWorkers = 20
+
+// maxRetries is the number of attempts made for a single device before
+// giving up and reporting it as failed.
+const maxRetries = 5
+
+// Archiver records every config push around the call, so callers can
+// keep an auditable history independent of this package. RecordRequest
+// is called before the RPC is issued and returns an opaque record ID;
+// RecordResult is called after a successful push with the resulting
+// config version.
+type Archiver interface {
+	RecordRequest(ctx context.Context, devicePath string, payload []byte) (recordID string, err error)
+	RecordResult(ctx context.Context, recordID string, devicePath string, payload []byte, version int64) error
+}
+
+// Client pushes device configs to a Cloud IoT Core registry over gRPC.
+type Client struct {
+	dm       *iot.DeviceManagerClient
+	archiver Archiver
+}
+
+// New dials the Cloud IoT Core DeviceManager API using
+// iot.DefaultAuthScopes() for credentials.
+func New(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	opts = append([]option.ClientOption{option.WithScopes(iot.DefaultAuthScopes()...)}, opts...)
+	dm, err := iot.NewDeviceManagerClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("iotclient: creating device manager client: %w", err)
+	}
+	return &Client{dm: dm}, nil
+}
+
+// Close tears down the underlying gRPC connection.
+func (c *Client) Close() error {
+	return c.dm.Close()
+}
+
+// SetArchiver wires an Archiver into the client so every subsequent
+// config push is recorded before and after the RPC. Pass nil to stop
+// archiving.
+func (c *Client) SetArchiver(a Archiver) {
+	c.archiver = a
+}
+
+// DeviceFilter reports whether a device should receive the pushed config.
+type DeviceFilter func(*iotpb.Device) bool
+
+// MetadataEquals matches devices whose Metadata[key] equals want.
+func MetadataEquals(key, want string) DeviceFilter {
+	return func(d *iotpb.Device) bool {
+		return d.GetMetadata()[key] == want
+	}
+}
+
+// HasGatewayConfig matches devices that are configured as gateways.
+func HasGatewayConfig() DeviceFilter {
+	return func(d *iotpb.Device) bool {
+		return d.GetGatewayConfig().GetGatewayType() == iotpb.GatewayType_GATEWAY
+	}
+}
+
+// MetadataMatches matches devices with at least one metadata value
+// matching re. Cloud IoT Core devices carry no separate label field -
+// labels live on the registry, not the device - so this is the closest
+// equivalent for a per-device regex filter.
+func MetadataMatches(re *regexp.Regexp) DeviceFilter {
+	return func(d *iotpb.Device) bool {
+		for _, v := range d.GetMetadata() {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PushConfig lists every device under registryPath, keeps the ones
+// matching filter (nil matches everything), and concurrently pushes cfg
+// to each of them via ModifyCloudToDeviceConfig. It returns an
+// aggregate error naming every device that failed after retries.
+func (c *Client) PushConfig(ctx context.Context, registryPath string, cfg []byte, filter DeviceFilter) error {
+	devices, err := c.listDevices(ctx, registryPath, filter)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, maxWorkers)
+		mu       sync.Mutex
+		failures []string
+	)
+
+	for _, d := range devices {
+		d := d
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := c.pushOne(ctx, d.GetName(), cfg); err != nil {
+				mu.Lock()
+				failures = append(failures, fmt.Sprintf("%s: %v", d.GetName(), err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("iotclient: failed to push config to %d device(s): %s", len(failures), failures)
+	}
+	return nil
+}
+
+// ListDevices lists every device under registryPath, keeping only the
+// ones matching filter (nil matches everything).
+func (c *Client) ListDevices(ctx context.Context, registryPath string, filter DeviceFilter) ([]*iotpb.Device, error) {
+	return c.listDevices(ctx, registryPath, filter)
+}
+
+func (c *Client) listDevices(ctx context.Context, registryPath string, filter DeviceFilter) ([]*iotpb.Device, error) {
+	it := c.dm.ListDevices(ctx, &iotpb.ListDevicesRequest{
+		Parent:   registryPath,
+		PageSize: 200,
+		// Without a field mask only id and num_id come back, and the
+		// metadata and gateway filters would see empty devices.
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "metadata", "gateway_config"}},
+	})
+
+	var devices []*iotpb.Device
+	for {
+		d, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("iotclient: listing devices in %s: %w", registryPath, err)
+		}
+		if filter == nil || filter(d) {
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+// GetDevice fetches a single device by its full resource path.
+func (c *Client) GetDevice(ctx context.Context, devicePath string) (*iotpb.Device, error) {
+	d, err := c.dm.GetDevice(ctx, &iotpb.GetDeviceRequest{Name: devicePath})
+	if err != nil {
+		return nil, fmt.Errorf("iotclient: getting device %s: %w", devicePath, err)
+	}
+	return d, nil
+}
+
+// PushConfigVersion pushes cfg to a single device, setting
+// VersionToUpdate to versionToOverride so the call is rejected instead
+// of silently clobbering a racing update. It returns the resulting
+// config version on success.
+func (c *Client) PushConfigVersion(ctx context.Context, devicePath string, cfg []byte, versionToOverride int64) (int64, error) {
+	recordID, err := c.archiveRequest(ctx, devicePath, cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	config, err := c.dm.ModifyCloudToDeviceConfig(ctx, &iotpb.ModifyCloudToDeviceConfigRequest{
+		Name:            devicePath,
+		VersionToUpdate: versionToOverride,
+		BinaryData:      cfg,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("iotclient: pushing config to %s: %w", devicePath, err)
+	}
+
+	c.archiveResult(ctx, recordID, devicePath, cfg, config.GetVersion())
+	return config.GetVersion(), nil
+}
+
+// archiveRequest records a pending push with the archiver, if one is
+// set. It returns an empty record ID and no error when there is no
+// archiver, so callers can pass it straight through unconditionally.
+func (c *Client) archiveRequest(ctx context.Context, devicePath string, cfg []byte) (string, error) {
+	if c.archiver == nil {
+		return "", nil
+	}
+	recordID, err := c.archiver.RecordRequest(ctx, devicePath, cfg)
+	if err != nil {
+		return "", fmt.Errorf("iotclient: archiving request for %s: %w", devicePath, err)
+	}
+	return recordID, nil
+}
+
+// archiveResult records a successful push with the archiver, if one is
+// set. The device config has already been modified by this point, so a
+// failure to archive the result must not fail the push itself -
+// callers that retry or Nack on error would otherwise re-push (and
+// bump the version) on every archiving hiccup. Archiving failures are
+// logged and swallowed.
+func (c *Client) archiveResult(ctx context.Context, recordID, devicePath string, cfg []byte, version int64) {
+	if c.archiver == nil {
+		return
+	}
+	if err := c.archiver.RecordResult(ctx, recordID, devicePath, cfg, version); err != nil {
+		fmt.Printf("iotclient: archiving result for %s: %v\n", devicePath, err)
+	}
+}
+
+// pushOne calls ModifyCloudToDeviceConfig for a single device,
+// retrying with exponential backoff when the RPC fails with Unavailable
+// or DeadlineExceeded.
+func (c *Client) pushOne(ctx context.Context, devicePath string, cfg []byte) error {
+	recordID, err := c.archiveRequest(ctx, devicePath, cfg)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<uint(attempt-1)) * 200 * time.Millisecond
+			jitter := time.Duration(rand.Int63n(int64(backoff / 2)))
+			select {
+			case <-time.After(backoff + jitter):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		config, err := c.dm.ModifyCloudToDeviceConfig(ctx, &iotpb.ModifyCloudToDeviceConfigRequest{
+			Name:       devicePath,
+			BinaryData: cfg,
+		})
+		if err == nil {
+			c.archiveResult(ctx, recordID, devicePath, cfg, config.GetVersion())
+			return nil
+		}
+		lastErr = err
+
+		if s, ok := status.FromError(err); !ok || (s.Code() != codes.Unavailable && s.Code() != codes.DeadlineExceeded) {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", maxRetries, lastErr)
+}
@@ -0,0 +1,72 @@
+// Package rules maps a weather.Observation to a fan action through a
+// YAML-configurable list of thresholds, so operators can tune when fans
+// turn on without redeploying the function.
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xinyinglim/articles/internal/weather"
+)
+
+// Action is the fan state a matching rule (or the engine's default)
+// produces.
+type Action struct {
+	On    bool `yaml:"on" json:"on"`
+	Speed int  `yaml:"speed" json:"speed"`
+}
+
+// Rule fires when the observation meets every threshold it sets; a nil
+// threshold is ignored.
+type Rule struct {
+	MinTempC       *float64 `yaml:"minTempC,omitempty"`
+	MinHumidityPct *float64 `yaml:"minHumidityPct,omitempty"`
+	MinAQI         *float64 `yaml:"minAQI,omitempty"`
+	Action         Action   `yaml:"action"`
+}
+
+// Engine evaluates Rules in order, in document order, returning the
+// first match's Action, or Default if none match.
+type Engine struct {
+	Rules   []Rule `yaml:"rules"`
+	Default Action `yaml:"default"`
+}
+
+// Load parses a rules document from YAML.
+func Load(data []byte) (*Engine, error) {
+	var e Engine
+	if err := yaml.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("rules: parsing config: %w", err)
+	}
+	return &e, nil
+}
+
+// LoadFile reads and parses a rules document from path.
+func LoadFile(path string) (*Engine, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rules: reading %s: %w", path, err)
+	}
+	return Load(data)
+}
+
+// Decide returns the Action for the first rule whose thresholds obs
+// meets, or e.Default if no rule matches.
+func (e *Engine) Decide(obs weather.Observation) Action {
+	for _, r := range e.Rules {
+		if r.MinTempC != nil && obs.TempC < *r.MinTempC {
+			continue
+		}
+		if r.MinHumidityPct != nil && obs.HumidityPct < *r.MinHumidityPct {
+			continue
+		}
+		if r.MinAQI != nil && obs.AQI < *r.MinAQI {
+			continue
+		}
+		return r.Action
+	}
+	return e.Default
+}
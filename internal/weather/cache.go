@@ -0,0 +1,68 @@
+package weather
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// CachingSource wraps a WeatherSource and reuses its Observations across
+// a grid cell for a TTL, so many devices near each other don't each
+// trigger a fresh upstream call and risk rate limiting.
+type CachingSource struct {
+	Source      WeatherSource
+	TTL         time.Duration
+	GridSizeDeg float64
+
+	mu    sync.Mutex
+	cache map[gridCell]cachedObservation
+}
+
+type gridCell struct {
+	lat, lon int64
+}
+
+type cachedObservation struct {
+	obs       Observation
+	expiresAt time.Time
+}
+
+// NewCaching returns a CachingSource over source, rounding lat/lon into
+// gridSizeDeg-wide cells and caching each cell's Observation for ttl.
+func NewCaching(source WeatherSource, ttl time.Duration, gridSizeDeg float64) *CachingSource {
+	return &CachingSource{
+		Source:      source,
+		TTL:         ttl,
+		GridSizeDeg: gridSizeDeg,
+		cache:       make(map[gridCell]cachedObservation),
+	}
+}
+
+func (c *CachingSource) Current(ctx context.Context, lat, lon float64) (Observation, error) {
+	cell := c.cellFor(lat, lon)
+
+	c.mu.Lock()
+	cached, ok := c.cache[cell]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.obs, nil
+	}
+
+	obs, err := c.Source.Current(ctx, lat, lon)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	c.mu.Lock()
+	c.cache[cell] = cachedObservation{obs: obs, expiresAt: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+	return obs, nil
+}
+
+func (c *CachingSource) cellFor(lat, lon float64) gridCell {
+	return gridCell{
+		lat: int64(math.Floor(lat / c.GridSizeDeg)),
+		lon: int64(math.Floor(lon / c.GridSizeDeg)),
+	}
+}
@@ -0,0 +1,89 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// NOAA is a WeatherSource backed by the National Weather Service API
+// (api.weather.gov), which needs no API key. It reports temperature and
+// humidity from the nearest observation station; NOAA has no air
+// quality feed, so Observation.AQI is always 0.
+type NOAA struct {
+	HTTPClient *http.Client
+	// UserAgent identifies the caller, as required by api.weather.gov.
+	UserAgent string
+}
+
+// NewNOAA returns a NOAA source. userAgent should be a contact string
+// (e.g. "fan-controller, ops@example.com") as required by the NWS API
+// terms of use.
+func NewNOAA(userAgent string) *NOAA {
+	return &NOAA{HTTPClient: http.DefaultClient, UserAgent: userAgent}
+}
+
+func (n *NOAA) Current(ctx context.Context, lat, lon float64) (Observation, error) {
+	var points struct {
+		Properties struct {
+			ObservationStations string `json:"observationStations"`
+		} `json:"properties"`
+	}
+	pointsURL := fmt.Sprintf("https://api.weather.gov/points/%f,%f", lat, lon)
+	if err := n.getJSON(ctx, pointsURL, &points); err != nil {
+		return Observation{}, fmt.Errorf("noaa: resolving grid point: %w", err)
+	}
+
+	var stations struct {
+		Features []struct {
+			ID string `json:"id"`
+		} `json:"features"`
+	}
+	if err := n.getJSON(ctx, points.Properties.ObservationStations, &stations); err != nil {
+		return Observation{}, fmt.Errorf("noaa: listing observation stations: %w", err)
+	}
+	if len(stations.Features) == 0 {
+		return Observation{}, fmt.Errorf("noaa: no observation stations near %f,%f", lat, lon)
+	}
+
+	var latest struct {
+		Properties struct {
+			Temperature struct {
+				Value float64 `json:"value"`
+			} `json:"temperature"`
+			RelativeHumidity struct {
+				Value float64 `json:"value"`
+			} `json:"relativeHumidity"`
+		} `json:"properties"`
+	}
+	latestURL := stations.Features[0].ID + "/observations/latest"
+	if err := n.getJSON(ctx, latestURL, &latest); err != nil {
+		return Observation{}, fmt.Errorf("noaa: fetching latest observation: %w", err)
+	}
+
+	return Observation{
+		TempC:       latest.Properties.Temperature.Value,
+		HumidityPct: latest.Properties.RelativeHumidity.Value,
+	}, nil
+}
+
+func (n *NOAA) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", n.UserAgent)
+	req.Header.Set("Accept", "application/geo+json")
+
+	resp, err := n.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
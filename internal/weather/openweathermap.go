@@ -0,0 +1,93 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// OpenWeatherMap is a WeatherSource backed by the OpenWeatherMap Current
+// Weather and Air Pollution APIs.
+type OpenWeatherMap struct {
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewOpenWeatherMap returns an OpenWeatherMap source using apiKey and
+// http.DefaultClient.
+func NewOpenWeatherMap(apiKey string) *OpenWeatherMap {
+	return &OpenWeatherMap{APIKey: apiKey, HTTPClient: http.DefaultClient}
+}
+
+func (o *OpenWeatherMap) Current(ctx context.Context, lat, lon float64) (Observation, error) {
+	var weatherResp struct {
+		Main struct {
+			Temp     float64 `json:"temp"`
+			Humidity float64 `json:"humidity"`
+		} `json:"main"`
+	}
+	url := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?lat=%f&lon=%f&units=metric&appid=%s", lat, lon, o.APIKey)
+	if err := o.getJSON(ctx, url, &weatherResp); err != nil {
+		return Observation{}, fmt.Errorf("openweathermap: fetching current weather: %w", err)
+	}
+
+	var pollutionResp struct {
+		List []struct {
+			Main struct {
+				AQI float64 `json:"aqi"`
+			} `json:"main"`
+		} `json:"list"`
+	}
+	url = fmt.Sprintf("https://api.openweathermap.org/data/2.5/air_pollution?lat=%f&lon=%f&appid=%s", lat, lon, o.APIKey)
+	if err := o.getJSON(ctx, url, &pollutionResp); err != nil {
+		return Observation{}, fmt.Errorf("openweathermap: fetching air pollution: %w", err)
+	}
+
+	var owmIndex float64
+	if len(pollutionResp.List) > 0 {
+		owmIndex = pollutionResp.List[0].Main.AQI
+	}
+
+	return Observation{
+		TempC:       weatherResp.Main.Temp,
+		HumidityPct: weatherResp.Main.Humidity,
+		AQI:         owmAQIToEPA(owmIndex),
+	}, nil
+}
+
+// owmAQIToEPA maps OpenWeatherMap's 1 (Good) - 5 (Very Poor) categorical
+// air quality index to a representative point on the EPA-style 0-500
+// scale Observation.AQI uses, so rules.yaml's minAQI thresholds apply
+// uniformly across sources.
+func owmAQIToEPA(owmIndex float64) float64 {
+	switch {
+	case owmIndex <= 1:
+		return 25
+	case owmIndex == 2:
+		return 75
+	case owmIndex == 3:
+		return 125
+	case owmIndex == 4:
+		return 175
+	default:
+		return 250
+	}
+}
+
+func (o *OpenWeatherMap) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
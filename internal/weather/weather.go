@@ -0,0 +1,21 @@
+// Package weather provides pluggable sources of current-conditions data
+// so a device controller can react to real weather instead of a
+// hard-coded config.
+package weather
+
+import "context"
+
+// Observation is the current-conditions reading for a location. AQI is
+// on the EPA-style 0-500 scale (the scale rules.yaml's minAQI thresholds
+// assume); sources that report on a different scale must convert.
+// NOAA has no air quality feed and always reports AQI as 0.
+type Observation struct {
+	TempC       float64 `json:"tempC"`
+	HumidityPct float64 `json:"humidityPct"`
+	AQI         float64 `json:"aqi"`
+}
+
+// WeatherSource fetches the current Observation for a location.
+type WeatherSource interface {
+	Current(ctx context.Context, lat, lon float64) (Observation, error)
+}